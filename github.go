@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"time"
+
+	"github.com/google/go-github/v48/github"
+	"golang.org/x/oauth2"
+)
+
+// -------------------------------------------------------------
+// AUTHENTICATED, RATE-LIMIT-AWARE, RESUMABLE GITHUB SEARCH
+// -------------------------------------------------------------
+
+const searchStateFile = "state.json"
+
+// searchState is the resume cursor persisted to searchStateFile so a killed
+// run can pick up where it left off instead of re-paginating from page 1.
+//
+// ConsumedInLastPage counts how many of LastPage's results a previous run
+// actually kept, as opposed to how many were fetched: a run capped by -max
+// can stop partway through a page, and the untaken remainder must still be
+// reachable by a later run with a larger -max. On resume, LastPage is
+// re-fetched (pages aren't addressable at finer granularity than this) and
+// its first ConsumedInLastPage results are skipped rather than treated as
+// new.
+type searchState struct {
+	Query              string `json:"query"`
+	LastPage           int    `json:"last_page"`
+	LastRepo           string `json:"last_repo"`
+	ConsumedInLastPage int    `json:"consumed_in_last_page"`
+}
+
+func loadSearchState(path, query string) *searchState {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return &searchState{Query: query, LastPage: 1}
+	}
+	var s searchState
+	if err := json.Unmarshal(data, &s); err != nil || s.Query != query {
+		return &searchState{Query: query, LastPage: 1}
+	}
+	log.Printf("Resuming search %q from page %d (last repo: %s)", query, s.LastPage, s.LastRepo)
+	return &s
+}
+
+func (s *searchState) save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// newGitHubClient builds a github.Client authenticated with token (falling
+// back to GITHUB_TOKEN), or an anonymous client if neither is set. An
+// authenticated client gets a much higher rate limit (5000 req/hr vs. 60)
+// and up to 100 results per search page instead of 10.
+func newGitHubClient(ctx context.Context, token string) *github.Client {
+	if token == "" {
+		token = os.Getenv("GITHUB_TOKEN")
+	}
+	if token == "" {
+		return github.NewClient(oauth2.NewClient(ctx, nil))
+	}
+	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+	return github.NewClient(oauth2.NewClient(ctx, ts))
+}
+
+// searchRepositories runs query against client.Search.Repositories,
+// paginating until maxResults repositories are collected (or the results
+// run out) and resuming from searchStateFile if a
+// previous run for the same query was interrupted. On a rate-limit error it
+// sleeps until the limit resets and retries the same page rather than
+// failing the whole run.
+func searchRepositories(ctx context.Context, client *github.Client, query string, maxResults int) ([]*github.Repository, error) {
+	state := loadSearchState(searchStateFile, query)
+
+	opts := &github.SearchOptions{
+		Sort:  "stars",
+		Order: "desc",
+		ListOptions: github.ListOptions{
+			Page:    state.LastPage,
+			PerPage: 100,
+		},
+	}
+
+	var repos []*github.Repository
+	for len(repos) < maxResults {
+		result, resp, err := client.Search.Repositories(ctx, query, opts)
+		if err != nil {
+			var rateErr *github.RateLimitError
+			if errors.As(err, &rateErr) {
+				wait := time.Until(rateErr.Rate.Reset.Time)
+				log.Printf("Rate limited; sleeping %s until reset", wait)
+				time.Sleep(wait)
+				continue
+			}
+			return repos, err
+		}
+
+		// If this is the page we resumed into, skip the results a prior run
+		// already kept instead of treating the whole page as new.
+		pageRepos := result.Repositories
+		priorOffset := 0
+		if opts.Page == state.LastPage {
+			priorOffset = state.ConsumedInLastPage
+			if priorOffset >= len(pageRepos) {
+				pageRepos = nil
+			} else {
+				pageRepos = pageRepos[priorOffset:]
+			}
+		}
+
+		before := len(repos)
+		repos = append(repos, pageRepos...)
+		kept := len(repos)
+		if kept > maxResults {
+			kept = maxResults
+		}
+		consumed := kept - before
+		if consumed > 0 {
+			state.LastRepo = pageRepos[consumed-1].GetFullName()
+		}
+
+		if consumed < len(pageRepos) {
+			// maxResults cut us off partway through this page; remember the
+			// offset so a later run with a larger -max picks up the unused
+			// remainder instead of skipping straight to the next page.
+			state.LastPage = opts.Page
+			state.ConsumedInLastPage = priorOffset + consumed
+			_ = state.save(searchStateFile)
+			break
+		}
+
+		state.ConsumedInLastPage = 0
+		if resp.NextPage == 0 {
+			state.LastPage = 0
+			_ = state.save(searchStateFile)
+			break
+		}
+		opts.Page = resp.NextPage
+		state.LastPage = resp.NextPage
+		if err := state.save(searchStateFile); err != nil {
+			log.Printf("Error saving search state: %v", err)
+		}
+	}
+
+	if len(repos) > maxResults {
+		repos = repos[:maxResults]
+	}
+	return repos, nil
+}
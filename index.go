@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/gob"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// -------------------------------------------------------------
+// TRIGRAM INDEX (Zoekt-style) OVER PREVIOUSLY CLONED REPOS
+// -------------------------------------------------------------
+//
+// Running analyzeLocalRepo re-parses every repo from scratch each time.
+// The index/search subcommands instead build a persistent, queryable
+// corpus: every string literal ever seen, tagged with the identifiers in
+// lexical scope at that point (literalObservation, from observeRepoLiterals
+// in resolve.go), is stored as a posting and indexed by trigram so "does
+// identifier X appear in some literal, across however many repos" is a
+// lookup instead of a re-parse.
+
+// indexPosting is a literalObservation tied back to the repo it came from,
+// i.e. a single entry in the trigram index.
+type indexPosting struct {
+	Repo    string
+	File    string
+	Line    int
+	Scope   []string
+	Literal string
+}
+
+// trigramIndex maps every 3-byte substring of a literal to the postings
+// that contain it, so searching for an identifier only has to look at
+// postings sharing its trigrams instead of scanning the whole corpus.
+type trigramIndex struct {
+	Postings []indexPosting
+	Trigrams map[string][]int
+}
+
+func newTrigramIndex() *trigramIndex {
+	return &trigramIndex{Trigrams: make(map[string][]int)}
+}
+
+// trigramsOf returns the distinct lowercase 3-byte substrings of s.
+func trigramsOf(s string) []string {
+	s = strings.ToLower(s)
+	if len(s) < 3 {
+		return nil
+	}
+	seen := make(map[string]struct{})
+	var out []string
+	for i := 0; i+3 <= len(s); i++ {
+		tg := s[i : i+3]
+		if _, ok := seen[tg]; ok {
+			continue
+		}
+		seen[tg] = struct{}{}
+		out = append(out, tg)
+	}
+	return out
+}
+
+// add appends p as a new posting and files it under each of its literal's
+// trigrams.
+func (idx *trigramIndex) add(p indexPosting) {
+	id := len(idx.Postings)
+	idx.Postings = append(idx.Postings, p)
+	for _, tg := range trigramsOf(p.Literal) {
+		idx.Trigrams[tg] = append(idx.Trigrams[tg], id)
+	}
+}
+
+func (idx *trigramIndex) saveToFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return gob.NewEncoder(f).Encode(idx)
+}
+
+func loadIndexFile(path string) (*trigramIndex, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	idx := newTrigramIndex()
+	if err := gob.NewDecoder(f).Decode(idx); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// candidateIDs returns the posting IDs sharing every trigram of
+// identifier. Identifiers shorter than 3 bytes can't be trigram-filtered,
+// so every posting is returned in that case.
+func (idx *trigramIndex) candidateIDs(identifier string) []int {
+	tgs := trigramsOf(identifier)
+	if len(tgs) == 0 {
+		ids := make([]int, len(idx.Postings))
+		for i := range ids {
+			ids[i] = i
+		}
+		return ids
+	}
+
+	ids := append([]int(nil), idx.Trigrams[tgs[0]]...)
+	for _, tg := range tgs[1:] {
+		ids = intersectSorted(ids, idx.Trigrams[tg])
+	}
+	return ids
+}
+
+// intersectSorted merges two ascending, duplicate-free ID slices.
+func intersectSorted(a, b []int) []int {
+	var out []int
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, a[i])
+			i++
+			j++
+		case a[i] < b[j]:
+			i++
+		default:
+			j++
+		}
+	}
+	return out
+}
+
+// matchingPostings returns every posting whose literal actually contains
+// identifier (per containsIdentifier) with identifier in scope there.
+func (idx *trigramIndex) matchingPostings(identifier string) []indexPosting {
+	var out []indexPosting
+	for _, id := range idx.candidateIDs(identifier) {
+		p := idx.Postings[id]
+		if !containsIdentifier(p.Literal, identifier) {
+			continue
+		}
+		if !scopeContains(p.Scope, identifier) {
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+func scopeContains(scope []string, identifier string) bool {
+	for _, s := range scope {
+		if s == identifier {
+			return true
+		}
+	}
+	return false
+}
+
+// -------------------------------------------------------------
+// INDEX BUILDING
+// -------------------------------------------------------------
+
+// buildIndex walks every "repo-*" directory under root (the clone
+// directories left behind by earlier runs), type-checks each as a module,
+// and indexes every string literal found along with its lexical scope (see
+// observeRepoLiterals in resolve.go).
+func buildIndex(root string) (*trigramIndex, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := newTrigramIndex()
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), "repo-") {
+			continue
+		}
+		repoDir := filepath.Join(root, entry.Name())
+		repoName := strings.TrimPrefix(entry.Name(), "repo-")
+
+		observations, _, err := observeRepoLiterals(repoDir)
+		if err != nil && len(observations) == 0 {
+			continue
+		}
+		for _, obs := range observations {
+			idx.add(indexPosting{
+				Repo:    repoName,
+				File:    obs.File,
+				Line:    obs.Line,
+				Scope:   obs.VisibleNames,
+				Literal: obs.Text,
+			})
+		}
+	}
+	return idx, nil
+}
+
+// -------------------------------------------------------------
+// "index" / "search" SUBCOMMANDS
+// -------------------------------------------------------------
+
+func runIndexCmd(args []string) {
+	fs := flag.NewFlagSet("index", flag.ExitOnError)
+	outPath := fs.String("out", "identfinder.index", "path to write the trigram index")
+	fs.Parse(args)
+
+	idx, err := buildIndex(".")
+	if err != nil {
+		log.Fatalf("Error building index: %v", err)
+	}
+	if err := idx.saveToFile(*outPath); err != nil {
+		log.Fatalf("Error writing index to %s: %v", *outPath, err)
+	}
+	fmt.Printf("Indexed %d string literals (%d trigrams) from repo-* directories into %s\n",
+		len(idx.Postings), len(idx.Trigrams), *outPath)
+}
+
+func runSearchCmd(args []string) {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	indexPath := fs.String("index", "identfinder.index", "path to a trigram index built by the index subcommand")
+	identifier := fs.String("identifier", "", "identifier to look for across indexed string literals")
+	minRepos := fs.Int("min-repos", 1, "only report the identifier if it shadows a literal in at least this many distinct repos")
+	fs.Parse(args)
+
+	if *identifier == "" {
+		log.Fatalf("search requires -identifier")
+	}
+
+	idx, err := loadIndexFile(*indexPath)
+	if err != nil {
+		log.Fatalf("Error loading index %s: %v", *indexPath, err)
+	}
+
+	matches := idx.matchingPostings(*identifier)
+	repos := make(map[string]struct{})
+	for _, m := range matches {
+		repos[m.Repo] = struct{}{}
+	}
+	if len(repos) < *minRepos {
+		fmt.Printf("%s shadows a literal in %d repos, below -min-repos=%d\n", *identifier, len(repos), *minRepos)
+		return
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Repo != matches[j].Repo {
+			return matches[i].Repo < matches[j].Repo
+		}
+		return matches[i].Line < matches[j].Line
+	})
+
+	for _, m := range matches {
+		fmt.Printf("%s:%s:%d -> identifier=%s; literal=%q\n", m.Repo, m.File, m.Line, *identifier, m.Literal)
+	}
+	fmt.Printf("%s shadows a literal in %d distinct repos (%d occurrences)\n", *identifier, len(repos), len(matches))
+}
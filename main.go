@@ -5,38 +5,59 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"go/ast"
-	"go/parser"
-	"go/token"
 	"log"
 	"os"
-	"os/exec"
-	"path/filepath"
 	"strings"
+	"sync"
 	"unicode"
-
-	"github.com/google/go-github/v48/github"
-	"golang.org/x/oauth2"
 )
 
 // -------------------------------------------------------------
 // Global counters for ratio calculation
 // -------------------------------------------------------------
 var (
+	countersMu     sync.Mutex
 	totalStrings   int
 	matchedStrings int
 )
 
+// quiet suppresses non-error log output; runTasks sets it while repos are
+// being processed in parallel, since interleaved per-repo logs from
+// multiple workers aren't useful. logf is a no-op while quiet is true.
+var quiet bool
+
+func logf(format string, args ...interface{}) {
+	if quiet {
+		return
+	}
+	log.Printf(format, args...)
+}
+
 // matchInfo holds details about each matched string literal.
 type matchInfo struct {
-	File       string
-	LineNumber int
-	Identifier string
-	StringText string
-	EntireLine string
+	File         string
+	LineNumber   int
+	Identifier   string
+	StringText   string
+	EntireLine   string
+	TemplateRefs []string
 }
 
 func main() {
+	// The "index" and "search" subcommands operate on previously cloned
+	// repo-* directories and don't participate in the clone-and-analyze
+	// flow below, so dispatch them before anything else.
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "index":
+			runIndexCmd(os.Args[2:])
+			return
+		case "search":
+			runSearchCmd(os.Args[2:])
+			return
+		}
+	}
+
 	// -------------------------------------------
 	// Redirect log output to "result.log"
 	// -------------------------------------------
@@ -52,26 +73,36 @@ func main() {
 		minStars   int
 		maxStars   int
 		maxResults int
+		parallel   int
+		token      string
+		kinds      string
 	)
 
 	flag.IntVar(&minStars, "stars", 1000, "Minimum number of stars for search")
 	flag.IntVar(&maxStars, "maxstars", 9000, "Maximum number of stars for search")
 	flag.IntVar(&maxResults, "max", 5, "Max number of repositories to process (when searching)")
+	flag.IntVar(&parallel, "parallel", 1, "Number of repos to clone and analyze concurrently")
+	flag.StringVar(&outputFormat, "format", "text", "Match report format: text, json, or sarif")
+	flag.StringVar(&token, "token", "", "GitHub API token (falls back to GITHUB_TOKEN); raises the search rate limit and page size")
+	flag.StringVar(&kinds, "kinds", "", "Comma-separated object kinds to match (var,const,type,func); empty means all kinds")
+	flag.StringVar(&templateMode, "template-mode", templateMode, "How to treat {{template}}/${var} literals: strict, loose, or ignore")
 
 	// Parse flags
 	flag.Parse()
+	kindsFilter = parseKinds(kinds)
 
 	// If the user provided arguments after the flags, interpret them as GitHub repos.
 	args := flag.Args()
 
+	var tasks []repoTask
+
 	if len(args) > 0 {
 		// --------------------------------------------------------------------
 		// CASE 1: The user specified one or more repos directly in the args
 		// --------------------------------------------------------------------
 		log.Println("Positional arguments detected. Skipping GitHub search.")
 		for _, repoPath := range args {
-			log.Printf("Analyzing requested GitHub repo: %s\n", repoPath)
-			analyzeSingleGitHubRepo(repoPath)
+			tasks = append(tasks, newRepoTaskFromPath(repoPath))
 		}
 
 	} else {
@@ -79,49 +110,25 @@ func main() {
 		// CASE 2: No positional args => Use the GitHub search logic
 		// --------------------------------------------------------------------
 		ctx := context.Background()
-		tc := oauth2.NewClient(ctx, nil)
-		client := github.NewClient(tc)
+		client := newGitHubClient(ctx, token)
 
 		// Build the search query with star range
 		query := fmt.Sprintf("language:Go stars:%d..%d", minStars, maxStars)
-		searchOpts := &github.SearchOptions{
-			Sort:  "stars",
-			Order: "desc",
-			ListOptions: github.ListOptions{
-				PerPage: maxResults,
-			},
-		}
 
-		result, _, err := client.Search.Repositories(ctx, query, searchOpts)
+		repos, err := searchRepositories(ctx, client, query, maxResults)
 		if err != nil {
 			log.Fatalf("Error searching repositories: %v", err)
 		}
 
-		for i, repo := range result.Repositories {
-			if i >= maxResults {
-				break
-			}
+		for _, repo := range repos {
 			log.Printf("Scanning repository %s (stars=%d)\n",
 				repo.GetFullName(), repo.GetStargazersCount())
-
-			// Construct local directory name for cloning (if needed)
-			tmpDir := fmt.Sprintf("repo-%s", strings.ReplaceAll(repo.GetFullName(), "/", "-"))
-
-			// If directory doesn't exist, clone
-			if _, statErr := os.Stat(tmpDir); os.IsNotExist(statErr) {
-				if err := cloneRepo(repo.GetCloneURL(), tmpDir); err != nil {
-					log.Printf("Error cloning %s: %v", repo.GetFullName(), err)
-					continue
-				}
-			} else {
-				log.Printf("Directory %q already exists, skipping clone", tmpDir)
-			}
-
-			// Analyze the local repo (already cloned or existing)
-			analyzeLocalRepo(tmpDir, repo.GetFullName())
+			tasks = append(tasks, newRepoTaskFromGitHub(repo))
 		}
 	}
 
+	runTasks(tasks, parallel)
+
 	// At the end, print overall ratio of “strings that contained an identifier” to “total strings seen”
 	overallRatio := 0.0
 	if totalStrings > 0 {
@@ -131,63 +138,51 @@ func main() {
 		overallRatio, matchedStrings, totalStrings)
 }
 
-// -------------------------------------------------------------
-// GITHUB REPO CLONING & ANALYSIS
-// -------------------------------------------------------------
-
-// analyzeSingleGitHubRepo takes a GitHub repo path like "github.com/user/repo".
-// If the local clone folder doesn't exist, it clones from "https://github.com/user/repo.git"
-// into "repo-github.com-user-repo". Then analyzes it.
-func analyzeSingleGitHubRepo(repoPath string) {
-	// Build a clone URL, e.g. "https://github.com/user/repo.git"
-	cloneURL := "https://" + strings.TrimSuffix(repoPath, ".git") + ".git"
-
-	// Local folder name, e.g. "repo-github.com-user-repo"
-	localDir := fmt.Sprintf("repo-%s", strings.ReplaceAll(repoPath, "/", "-"))
-
-	// If directory doesn't exist, clone
-	if _, err := os.Stat(localDir); os.IsNotExist(err) {
-		log.Printf("Cloning %s into %s\n", cloneURL, localDir)
-		if err := cloneRepo(cloneURL, localDir); err != nil {
-			log.Printf("Error cloning %s: %v", cloneURL, err)
-			return
-		}
-	} else {
-		log.Printf("Directory %q already exists, skipping clone", localDir)
-	}
-
-	// Analyze that local directory
-	analyzeLocalRepo(localDir, repoPath)
-}
-
-// cloneRepo does a shallow clone from the given gitURL into dest
-func cloneRepo(gitURL, dest string) error {
-	log.Printf("Cloning %s into %s", gitURL, dest)
-	cmd := exec.Command("git", "clone", "--depth=1", gitURL, dest)
-	return cmd.Run()
-}
-
 // -------------------------------------------------------------
 // LOCAL REPO ANALYSIS
 // -------------------------------------------------------------
 
-// analyzeLocalRepo walks all Go files in repoDir, analyzes them, prints stats,
-// and writes match logs to a file named "<repoName>-matches.log" if there are matches.
+// analyzeLocalRepo type-checks repoDir as a Go module, inspects every
+// string literal against the exact set of identifiers in lexical scope
+// there (see resolve.go), prints stats, and writes matches to a report file
+// named "<repoName>-matches.log" (".json" or ".sarif" per -format) if there
+// are matches.
 func analyzeLocalRepo(repoDir, repoName string) {
-	goFiles := gatherGoFiles(repoDir)
+	observations, totalLines, err := observeRepoLiterals(repoDir)
+	if err != nil && len(observations) == 0 {
+		log.Printf("Repository %s: error loading packages: %v", repoName, err)
+		return
+	}
 
-	var totalLines, stringLines, repoTotalStrings, repoMatchedStrings int
+	var stringLines, repoTotalStrings, repoMatchedStrings int
 	var allMatches []matchInfo
 
-	for _, fpath := range goFiles {
-		lines, strLines, matchedStrs, matches := analyzeFileWithLines(fpath)
-		totalLines += lines
-		stringLines += strLines
-		repoTotalStrings += strLines
-		repoMatchedStrings += matchedStrs
-		allMatches = append(allMatches, matches...)
+	for _, obs := range observations {
+		repoTotalStrings++
+		stringLines++
+
+		if obs.MatchedIdentifier == "" {
+			continue
+		}
+		repoMatchedStrings++
+
+		allMatches = append(allMatches, matchInfo{
+			File:         obs.File,
+			LineNumber:   obs.Line,
+			Identifier:   obs.MatchedIdentifier,
+			StringText:   obs.Text,
+			EntireLine:   obs.EntireLine,
+			TemplateRefs: obs.TemplateRefs,
+		})
 	}
 
+	// Repos run concurrently when -parallel > 1, so the shared counters
+	// need a mutex; take it once per repo rather than once per literal.
+	countersMu.Lock()
+	totalStrings += repoTotalStrings
+	matchedStrings += repoMatchedStrings
+	countersMu.Unlock()
+
 	// Calculate ratios for the repository
 	repoLineRatio := 0.0
 	if totalLines > 0 {
@@ -198,80 +193,21 @@ func analyzeLocalRepo(repoDir, repoName string) {
 		repoStringRatio = float64(repoMatchedStrings) / float64(repoTotalStrings)
 	}
 
-	log.Printf("Repository %s: String-to-total-line ratio: %.4f (%d string lines / %d total lines)\n",
+	logf("Repository %s: String-to-total-line ratio: %.4f (%d string lines / %d total lines)\n",
 		repoName, repoLineRatio, stringLines, totalLines)
-	log.Printf("Repository %s: Matched-strings-to-total-strings ratio: %.4f (%d matched / %d total strings)\n",
+	logf("Repository %s: Matched-strings-to-total-strings ratio: %.4f (%d matched / %d total strings)\n",
 		repoName, repoStringRatio, repoMatchedStrings, repoTotalStrings)
 
-	// If matches found, write them to a log file
+	// If matches found, write them to a report file in -format
 	if len(allMatches) > 0 {
-		logFile := strings.ReplaceAll(repoName, "/", "-") + "-matches.log"
-		f, err := os.Create(logFile)
+		reportFile, err := writeMatchReport(repoName, allMatches)
 		if err != nil {
-			log.Printf("Error creating report file %s: %v", logFile, err)
+			log.Printf("Error creating report file %s: %v", reportFile, err)
 			return
 		}
-		defer f.Close()
-
-		for _, m := range allMatches {
-			line := fmt.Sprintf(
-				"%s:%d -> identifier=%s; string=%q; entire_line=%q\n",
-				m.File, m.LineNumber, m.Identifier, m.StringText, m.EntireLine,
-			)
-			_, _ = f.WriteString(line)
-		}
-		log.Printf("Wrote %d matches for %s to %s\n",
-			len(allMatches), repoName, logFile)
-	}
-}
-
-// gatherGoFiles recursively gathers all .go files under the specified root directory.
-func gatherGoFiles(root string) []string {
-	var goFiles []string
-	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-		if err == nil && !info.IsDir() && strings.HasSuffix(path, ".go") {
-			goFiles = append(goFiles, path)
-		}
-		return nil
-	})
-	return goFiles
-}
-
-// -------------------------------------------------------------
-// FILE-LEVEL ANALYSIS
-// -------------------------------------------------------------
-
-// analyzeFileWithLines parses a single Go file, counts total lines, and inspects
-// string literals to see if they contain any in-scope identifiers. Returns
-// line counts, match counts, and a slice of matchInfo (including the entire line).
-func analyzeFileWithLines(filePath string) (int, int, int, []matchInfo) {
-	// Read all lines so we can log the entire line if there's a match
-	srcLines, err := readFileLines(filePath)
-	if err != nil || len(srcLines) == 0 {
-		return 0, 0, 0, nil
-	}
-
-	fset := token.NewFileSet()
-	node, err := parser.ParseFile(fset, filePath, nil, parser.AllErrors)
-	if err != nil {
-		return 0, 0, 0, nil
-	}
-
-	// Determine total line count from the token.File
-	fileObj := fset.File(node.Pos())
-	if fileObj == nil {
-		return 0, 0, 0, nil
+		logf("Wrote %d matches for %s to %s\n",
+			len(allMatches), repoName, reportFile)
 	}
-	totalLines := fileObj.LineCount()
-
-	// Use our scopeVisitor to track local variables, function parameters, etc.
-	v := newScopeVisitor(fset, filePath, srcLines)
-	ast.Walk(v, node)
-
-	// v.stringCount: how many string literals in this file
-	// v.matchCount: how many matched an in-scope identifier
-	// v.matches: the details of each match
-	return totalLines, v.stringCount, v.matchCount, v.matches
 }
 
 // readFileLines returns a slice of all lines in the given file.
@@ -291,181 +227,9 @@ func readFileLines(filePath string) ([]string, error) {
 }
 
 // -------------------------------------------------------------
-// SCOPE VISITOR & IDENTIFIER MATCHING
+// IDENTIFIER MATCHING
 // -------------------------------------------------------------
 
-// scopeVisitor holds state for AST traversal, including a stack of scopes
-// that track which identifiers (vars, params, etc.) are in scope.
-type scopeVisitor struct {
-	fset        *token.FileSet
-	filePath    string
-	srcLines    []string
-	matches     []matchInfo
-	stringCount int
-	matchCount  int
-
-	scopeStack []*scope
-}
-
-// scope is a collection of in-scope identifier names
-type scope struct {
-	names map[string]struct{}
-}
-
-func newScopeVisitor(fset *token.FileSet, filePath string, srcLines []string) *scopeVisitor {
-	return &scopeVisitor{
-		fset:     fset,
-		filePath: filePath,
-		srcLines: srcLines,
-		scopeStack: []*scope{
-			{names: make(map[string]struct{})}, // global scope
-		},
-	}
-}
-
-func (v *scopeVisitor) pushScope() {
-	v.scopeStack = append(v.scopeStack, &scope{names: make(map[string]struct{})})
-}
-
-func (v *scopeVisitor) popScope() {
-	v.scopeStack = v.scopeStack[:len(v.scopeStack)-1]
-}
-
-// addName adds an identifier to the top scope in the stack
-func (v *scopeVisitor) addName(name string) {
-	top := v.scopeStack[len(v.scopeStack)-1]
-	top.names[name] = struct{}{}
-}
-
-// inScope returns a list of all names in all active scopes
-func (v *scopeVisitor) inScope() []string {
-	var results []string
-	for _, s := range v.scopeStack {
-		for n := range s.names {
-			results = append(results, n)
-		}
-	}
-	return results
-}
-
-// Visit implements the ast.Visitor interface
-func (v *scopeVisitor) Visit(n ast.Node) ast.Visitor {
-	switch node := n.(type) {
-
-	case *ast.File:
-		// We'll walk node.Decls anyway, so just return v
-		return v
-
-	case *ast.FuncDecl:
-		// Push a scope for the function
-		v.pushScope()
-		// Add the function name
-		v.addName(node.Name.Name)
-		// Add function parameters
-		if node.Type.Params != nil {
-			for _, param := range node.Type.Params.List {
-				for _, pName := range param.Names {
-					v.addName(pName.Name)
-				}
-			}
-		}
-		// Add named result parameters
-		if node.Type.Results != nil {
-			for _, result := range node.Type.Results.List {
-				for _, rName := range result.Names {
-					v.addName(rName.Name)
-				}
-			}
-		}
-		// Walk the function body
-		if node.Body != nil {
-			ast.Walk(v, node.Body)
-		}
-		v.popScope()
-		// Return nil so we don't re-walk
-		return nil
-
-	case *ast.BlockStmt:
-		// Push a scope for each block
-		v.pushScope()
-		for _, stmt := range node.List {
-			ast.Walk(v, stmt)
-		}
-		v.popScope()
-		return nil
-
-	case *ast.AssignStmt:
-		// For short variable declarations: x := 123
-		if node.Tok.String() == ":=" {
-			for _, lh := range node.Lhs {
-				if ident, ok := lh.(*ast.Ident); ok {
-					v.addName(ident.Name)
-				}
-			}
-		}
-		return v
-
-	case *ast.DeclStmt:
-		// For local var/const/type declarations
-		if gen, ok := node.Decl.(*ast.GenDecl); ok {
-			for _, spec := range gen.Specs {
-				switch s := spec.(type) {
-				case *ast.ValueSpec:
-					for _, n := range s.Names {
-						v.addName(n.Name)
-					}
-				case *ast.TypeSpec:
-					v.addName(s.Name.Name)
-				}
-			}
-		}
-		return v
-
-	case *ast.BasicLit:
-		// If it's a string, check for in-scope identifiers
-		if node.Kind == token.STRING {
-			v.stringCount++
-			v.checkString(node)
-		}
-	}
-
-	return v
-}
-
-// checkString checks the given string literal for any in-scope identifiers.
-func (v *scopeVisitor) checkString(basicLit *ast.BasicLit) {
-	literalText := strings.Trim(basicLit.Value, "`\"")
-	linePos := v.fset.Position(basicLit.Pos()).Line
-
-	// Check each in-scope identifier
-	names := v.inScope()
-	for _, name := range names {
-		if containsIdentifier(literalText, name) {
-			v.matchCount++
-			matchedStrings++
-
-			// Grab entire source line
-			entireLine := ""
-			if linePos-1 >= 0 && linePos-1 < len(v.srcLines) {
-				entireLine = v.srcLines[linePos-1]
-			}
-			// Record match
-			v.matches = append(v.matches, matchInfo{
-				File:       v.filePath,
-				LineNumber: linePos,
-				Identifier: name,
-				StringText: literalText,
-				EntireLine: entireLine,
-			})
-			// Stop after first matching identifier so we don't double-count
-			break
-		}
-	}
-
-	// Also increment the global total string count
-	totalStrings++
-}
-
 // containsIdentifier returns true if `id` appears in `literal` such that it’s
 // “surrounded by boundary characters” (space, punctuation, quotes, etc.)
 // and NOT preceded directly by '%' or '\'.
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/google/go-github/v48/github"
+	"golang.org/x/sync/errgroup"
+)
+
+// -------------------------------------------------------------
+// CONCURRENT FETCH + ANALYZE PIPELINE
+// -------------------------------------------------------------
+//
+// repoTask is everything needed to fetch one repo and hand it to
+// analyzeLocalRepo, independent of where it came from (a positional
+// argument or a GitHub search result).
+type repoTask struct {
+	displayName string // used in logs and in the "<name>-matches.*" report filename
+	vcs         *vcsCmd
+	cloneURL    string
+	localDir    string
+}
+
+func newRepoTaskFromPath(repoPath string) repoTask {
+	vcs := detectVCS(repoPath)
+	return repoTask{
+		displayName: repoPath,
+		vcs:         vcs,
+		cloneURL:    cloneURLFor(vcs, repoPath),
+		localDir:    fmt.Sprintf("repo-%s", strings.ReplaceAll(repoPath, "/", "-")),
+	}
+}
+
+func newRepoTaskFromGitHub(repo *github.Repository) repoTask {
+	return repoTask{
+		displayName: repo.GetFullName(),
+		vcs:         vcsGit,
+		cloneURL:    repo.GetCloneURL(),
+		localDir:    fmt.Sprintf("repo-%s", strings.ReplaceAll(repo.GetFullName(), "/", "-")),
+	}
+}
+
+// fetchAndAnalyze clones task's repo if it isn't already on disk, then runs
+// the usual local analysis on it.
+func fetchAndAnalyze(task repoTask) {
+	if _, err := os.Stat(task.localDir); os.IsNotExist(err) {
+		if err := cloneRepo(task.vcs, task.cloneURL, task.localDir); err != nil {
+			log.Printf("Error cloning %s: %v", task.displayName, err)
+			return
+		}
+	} else {
+		logf("Directory %q already exists, skipping clone", task.localDir)
+	}
+
+	analyzeLocalRepo(task.localDir, task.displayName)
+}
+
+// runTasks fetches and analyzes every task, running up to `parallel` of them
+// concurrently. parallel <= 1 falls back to the original serial behavior.
+// Per-repo counters are merged into the global totalStrings/matchedStrings
+// under countersMu as each analysis completes (see analyzeLocalRepo), so
+// it's safe to call this with any worker count.
+func runTasks(tasks []repoTask, parallel int) {
+	if parallel <= 1 {
+		for _, task := range tasks {
+			fetchAndAnalyze(task)
+		}
+		return
+	}
+
+	// Interleaved per-repo log lines from N workers are mostly noise, so
+	// silence everything but errors while running in parallel -- the same
+	// tradeoff ghq's "-parallel" flag makes.
+	quiet = true
+	defer func() { quiet = false }()
+
+	var g errgroup.Group
+	sem := make(chan struct{}, parallel)
+	for _, task := range tasks {
+		task := task
+		sem <- struct{}{}
+		g.Go(func() error {
+			defer func() { <-sem }()
+			fetchAndAnalyze(task)
+			return nil
+		})
+	}
+	_ = g.Wait()
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// -------------------------------------------------------------
+// MATCH REPORT FORMATS (text / json / sarif)
+// -------------------------------------------------------------
+
+// outputFormat is set from the -format flag; one of "text", "json", "sarif".
+var outputFormat = "text"
+
+const sarifRuleID = "identifier-in-string-literal"
+
+// matchJSON is the NDJSON representation of a matchInfo, one per line.
+type matchJSON struct {
+	File         string   `json:"file"`
+	Line         int      `json:"line"`
+	Identifier   string   `json:"identifier"`
+	StringText   string   `json:"string"`
+	EntireLine   string   `json:"entire_line"`
+	TemplateRefs []string `json:"template_refs,omitempty"`
+}
+
+// sarifLog, sarifRun, ... are the minimal subset of SARIF 2.1.0 needed to
+// report each match as a result.
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// writeMatchReport writes matches for repoName to disk in outputFormat,
+// returning the path written to. It replaces the single ad-hoc text writer
+// that used to live inline in analyzeLocalRepo.
+func writeMatchReport(repoName string, matches []matchInfo) (string, error) {
+	base := strings.ReplaceAll(repoName, "/", "-") + "-matches"
+
+	switch outputFormat {
+	case "json":
+		return base + ".json", writeMatchReportJSON(base+".json", matches)
+	case "sarif":
+		return base + ".sarif", writeMatchReportSARIF(base+".sarif", matches)
+	default:
+		return base + ".log", writeMatchReportText(base+".log", matches)
+	}
+}
+
+func writeMatchReportText(path string, matches []matchInfo) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	for _, m := range matches {
+		line := fmt.Sprintf(
+			"%s:%d -> identifier=%s; string=%q; entire_line=%q",
+			m.File, m.LineNumber, m.Identifier, m.StringText, m.EntireLine,
+		)
+		if len(m.TemplateRefs) > 0 {
+			line += fmt.Sprintf("; template_refs=%v", m.TemplateRefs)
+		}
+		if _, err := f.WriteString(line + "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeMatchReportJSON(path string, matches []matchInfo) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	for _, m := range matches {
+		err := enc.Encode(matchJSON{
+			File:         m.File,
+			Line:         m.LineNumber,
+			Identifier:   m.Identifier,
+			StringText:   m.StringText,
+			EntireLine:   m.EntireLine,
+			TemplateRefs: m.TemplateRefs,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeMatchReportSARIF(path string, matches []matchInfo) error {
+	results := make([]sarifResult, 0, len(matches))
+	for _, m := range matches {
+		text := fmt.Sprintf("identifier %q found in string literal %q (%s)", m.Identifier, m.StringText, m.EntireLine)
+		if len(m.TemplateRefs) > 0 {
+			text += fmt.Sprintf("; template_refs=%v", m.TemplateRefs)
+		}
+		results = append(results, sarifResult{
+			RuleID: sarifRuleID,
+			Message: sarifMessage{
+				Text: text,
+			},
+			Locations: []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: m.File},
+					Region:           sarifRegion{StartLine: m.LineNumber},
+				},
+			}},
+		})
+	}
+
+	logDoc := sarifLog{
+		Schema:  "https://raw.githubusercontent.com/oasis-tcs/sarif-spec/master/Schemata/sarif-schema-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name: "identfinder",
+				Rules: []sarifRule{{
+					ID:               sarifRuleID,
+					ShortDescription: sarifMessage{Text: "An in-scope identifier appears inside a string literal"},
+				}},
+			}},
+			Results: results,
+		}},
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(logDoc)
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// -------------------------------------------------------------
+// TYPE-AND-SCOPE AWARE IDENTIFIER RESOLUTION
+// -------------------------------------------------------------
+//
+// This replaces the old hand-rolled scopeVisitor (a manual stack of
+// declared names) with a real go/packages + go/types load of the repo's
+// module. At every string literal we ask go/types for the *types.Scope
+// that was actually in effect there and walk its parent chain, which
+// gives us the exact set of visible objects -- package-level, imported,
+// method receivers, closures, shadowing and all -- instead of "every name
+// declared anywhere in the file".
+
+// literalObservation is one string literal together with everything in
+// lexical scope where it was found. VisibleNames feeds the trigram index
+// (index.go); MatchedIdentifier/EntireLine feed the match report
+// (analyzeLocalRepo) once the -kinds filter has been applied.
+type literalObservation struct {
+	File              string
+	Line              int
+	Text              string
+	EntireLine        string
+	VisibleNames      []string
+	MatchedIdentifier string
+	TemplateRefs      []string
+}
+
+// kindsFilter, set from the -kinds flag, restricts which object kinds
+// ("var", "const", "type", "func", ...) can satisfy a match. Empty means
+// no filtering.
+var kindsFilter map[string]bool
+
+// parseKinds turns a comma-separated -kinds value ("var,const") into a
+// lookup set. An empty string means "no filter".
+func parseKinds(s string) map[string]bool {
+	if s == "" {
+		return nil
+	}
+	kinds := make(map[string]bool)
+	for _, k := range strings.Split(s, ",") {
+		k = strings.TrimSpace(k)
+		if k != "" {
+			kinds[k] = true
+		}
+	}
+	return kinds
+}
+
+// objectKind maps a types.Object to the short kind name used by -kinds.
+func objectKind(obj types.Object) string {
+	switch obj.(type) {
+	case *types.Var:
+		return "var"
+	case *types.Const:
+		return "const"
+	case *types.TypeName:
+		return "type"
+	case *types.Func:
+		return "func"
+	case *types.PkgName:
+		return "pkgname"
+	case *types.Label:
+		return "label"
+	case *types.Builtin:
+		return "builtin"
+	default:
+		return "other"
+	}
+}
+
+func kindAllowed(obj types.Object, kinds map[string]bool) bool {
+	if len(kinds) == 0 {
+		return true
+	}
+	if obj == nil {
+		return false
+	}
+	return kinds[objectKind(obj)]
+}
+
+// loadRepoPackages type-checks every package under repoDir. Repos that
+// don't build as a module (no go.mod, missing deps, ...) simply yield
+// fewer or no usable packages -- callers should tolerate partial results
+// rather than failing the whole repo.
+func loadRepoPackages(repoDir string) (*token.FileSet, []*packages.Package, error) {
+	fset := token.NewFileSet()
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedSyntax |
+			packages.NeedTypes | packages.NeedTypesInfo,
+		Dir:  repoDir,
+		Fset: fset,
+	}
+	pkgs, err := packages.Load(cfg, "./...")
+	return fset, pkgs, err
+}
+
+// observeRepoLiterals walks every package loaded from repoDir and returns
+// one literalObservation per string literal, plus the repo's total line
+// count. Each file is only scanned once even if go/packages loads it into
+// more than one package variant (e.g. a package and its "_test" variant).
+func observeRepoLiterals(repoDir string) (observations []literalObservation, totalLines int, err error) {
+	fset, pkgs, err := loadRepoPackages(repoDir)
+	if err != nil && len(pkgs) == 0 {
+		return nil, 0, err
+	}
+
+	seenFiles := make(map[string]bool)
+	for _, pkg := range pkgs {
+		if pkg.TypesInfo == nil {
+			continue
+		}
+		for _, file := range pkg.Syntax {
+			filePath := fset.Position(file.Pos()).Filename
+			if seenFiles[filePath] {
+				continue
+			}
+			seenFiles[filePath] = true
+
+			srcLines, _ := readFileLines(filePath)
+			if fileObj := fset.File(file.Pos()); fileObj != nil {
+				totalLines += fileObj.LineCount()
+			}
+
+			observations = append(observations, scanFileLiterals(fset, file, filePath, srcLines, pkg.Types, pkg.TypesInfo)...)
+		}
+	}
+	return observations, totalLines, nil
+}
+
+// scanFileLiterals inspects a single parsed file, tracking the types.Scope
+// in effect at each point exactly as go/types recorded it (rather than a
+// hand-maintained stack), and records one literalObservation per string
+// literal.
+func scanFileLiterals(fset *token.FileSet, file *ast.File, filePath string, srcLines []string, pkg *types.Package, info *types.Info) []literalObservation {
+	var observations []literalObservation
+
+	currentScope := pkg.Scope()
+	var scopeUndo []*types.Scope
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		if n == nil {
+			// Post-order hook (guaranteed by ast.Inspect whenever the
+			// matching pre-order call returned true): undo any scope push
+			// made for the node whose subtree just finished.
+			if len(scopeUndo) > 0 {
+				currentScope = scopeUndo[len(scopeUndo)-1]
+				scopeUndo = scopeUndo[:len(scopeUndo)-1]
+			}
+			return true
+		}
+
+		// go/types records a function's scope on its FuncType, not on the
+		// body block, so apply it to the whole FuncDecl/FuncLit subtree.
+		var scopeKey ast.Node = n
+		switch fn := n.(type) {
+		case *ast.FuncDecl:
+			scopeKey = fn.Type
+		case *ast.FuncLit:
+			scopeKey = fn.Type
+		}
+		if sc, ok := info.Scopes[scopeKey]; ok {
+			scopeUndo = append(scopeUndo, currentScope)
+			currentScope = sc
+		} else {
+			scopeUndo = append(scopeUndo, currentScope)
+		}
+
+		lit, ok := n.(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return true
+		}
+
+		literalText := strings.Trim(lit.Value, "`\"")
+		linePos := fset.Position(lit.Pos()).Line
+		entireLine := ""
+		if linePos-1 >= 0 && linePos-1 < len(srcLines) {
+			entireLine = srcLines[linePos-1]
+		}
+
+		resolveText := literalText
+		var templateRefs []string
+		if isTemplateLiteral(literalText) {
+			if templateMode == "ignore" {
+				return true
+			}
+			templateRefs = extractTemplateRefs(literalText)
+			// Don't let resolveVisible see the "{{", "}}" and "." that make
+			// up the template syntax itself -- only the plain text around it.
+			resolveText = stripTemplateSyntax(literalText)
+		}
+
+		names, matched := resolveVisible(currentScope, resolveText)
+		if len(templateRefs) > 0 && templateMode == "strict" {
+			matched = resolveTemplateRefMatch(currentScope, templateRefs)
+		}
+
+		observations = append(observations, literalObservation{
+			File:              filePath,
+			Line:              linePos,
+			Text:              literalText,
+			EntireLine:        entireLine,
+			VisibleNames:      names,
+			MatchedIdentifier: matched,
+			TemplateRefs:      templateRefs,
+		})
+		return true
+	})
+
+	return observations
+}
+
+// resolveVisible walks sc and its ancestors (stopping before the universe
+// scope of predeclared identifiers) and returns every visible name, plus
+// the first one that both appears in literalText and passes kindsFilter.
+func resolveVisible(sc *types.Scope, literalText string) (names []string, matched string) {
+	seen := make(map[string]struct{})
+	for s := sc; s != nil && s != types.Universe; s = s.Parent() {
+		for _, name := range s.Names() {
+			if _, ok := seen[name]; ok {
+				continue
+			}
+			seen[name] = struct{}{}
+			names = append(names, name)
+
+			if matched == "" && containsIdentifier(literalText, name) && kindAllowed(s.Lookup(name), kindsFilter) {
+				matched = name
+			}
+		}
+	}
+	return names, matched
+}
+
+// resolveTemplateRefMatch is resolveVisible's -template-mode=strict
+// counterpart: rather than trusting the template parser's field name
+// outright, it only counts a templateRef as a match once a visible
+// object actually named it (or, for a dotted ref like "user.Name", named
+// its leaf field/variable) and kindsFilter allows that object's kind.
+func resolveTemplateRefMatch(sc *types.Scope, refs []string) string {
+	for s := sc; s != nil && s != types.Universe; s = s.Parent() {
+		for _, name := range s.Names() {
+			if !kindAllowed(s.Lookup(name), kindsFilter) {
+				continue
+			}
+			for _, ref := range refs {
+				if templateRefMatchesName(ref, name) {
+					return name
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// templateRefMatchesName reports whether ref (e.g. "Name" or the dotted
+// "user.Name") refers to name.
+func templateRefMatchesName(ref, name string) bool {
+	if ref == name {
+		return true
+	}
+	if idx := strings.LastIndex(ref, "."); idx != -1 && ref[idx+1:] == name {
+		return true
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// -------------------------------------------------------------
+// TEMPLATE-EXPANSION DETECTION
+// -------------------------------------------------------------
+//
+// A string literal like `"Hello {{.Name}}"` or `"${name}"` isn't really
+// leaking an identifier into a string literal the way `"user.Name"` is --
+// it's a template that some other layer expands at runtime. Without this,
+// containsIdentifier happily "matches" every {{.Field}} and ${var} in a
+// codebase that leans on text/template or shell-style interpolation,
+// which swamps the identifier-in-string-literal ratio the tool exists to
+// measure.
+
+// templateMode is set from the -template-mode flag and controls how a
+// literalObservation handles a literal containing template syntax:
+//
+//   - "strict": the template's own field/variable refs count as the match
+//     (these genuinely are identifier references, just via template
+//     syntax instead of string concatenation)
+//   - "loose" (default): resolve the match against lexical scope using
+//     only the plain text outside the template spans (see
+//     stripTemplateSyntax), and record TemplateRefs for informational purposes
+//   - "ignore": drop the literal from the analysis entirely
+var templateMode = "loose"
+
+// dollarBraceRef matches shell/JS-style "${name}" interpolation, which
+// text/template doesn't parse but is just as common a templating idiom.
+var dollarBraceRef = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_.]*)\}`)
+
+// isTemplateLiteral reports whether literalText looks like it uses
+// template-expansion syntax rather than being a plain string.
+func isTemplateLiteral(literalText string) bool {
+	if strings.Contains(literalText, "{{") && strings.Contains(literalText, "}}") {
+		return true
+	}
+	return dollarBraceRef.MatchString(literalText)
+}
+
+// templateActionRef matches a whole "{{...}}" template action.
+var templateActionRef = regexp.MustCompile(`\{\{.*?\}\}`)
+
+// stripTemplateSyntax blanks out template-expansion spans ("{{.Name}}",
+// "${name}") from literalText, leaving the surrounding plain text intact.
+// Without this, resolveVisible would match lexical identifiers against the
+// "{{", "}}" and "." punctuation (and the field names themselves) that make
+// up the template syntax -- exactly the false positives this mode exists to
+// remove. The refs themselves are still surfaced separately via
+// extractTemplateRefs/TemplateRefs.
+func stripTemplateSyntax(literalText string) string {
+	s := templateActionRef.ReplaceAllString(literalText, " ")
+	s = dollarBraceRef.ReplaceAllString(s, " ")
+	return s
+}
+
+// extractTemplateRefs returns the field/variable names referenced by
+// literalText's template syntax, e.g. ["Name", "User.Role"] for
+// `"Hi {{.Name}}, role={{.User.Role}}"`.
+func extractTemplateRefs(literalText string) []string {
+	var refs []string
+
+	if strings.Contains(literalText, "{{") {
+		if tmpl, err := template.New("literal").Parse(literalText); err == nil && tmpl.Tree != nil {
+			walkTemplateNode(tmpl.Tree.Root, &refs)
+		}
+	}
+
+	for _, m := range dollarBraceRef.FindAllStringSubmatch(literalText, -1) {
+		refs = append(refs, m[1])
+	}
+
+	return refs
+}
+
+// walkTemplateNode collects every FieldNode/VariableNode reference under n
+// into refs.
+func walkTemplateNode(n parse.Node, refs *[]string) {
+	if n == nil {
+		return
+	}
+	switch t := n.(type) {
+	case *parse.ListNode:
+		for _, c := range t.Nodes {
+			walkTemplateNode(c, refs)
+		}
+	case *parse.ActionNode:
+		walkTemplateNode(t.Pipe, refs)
+	case *parse.PipeNode:
+		for _, cmd := range t.Cmds {
+			walkTemplateNode(cmd, refs)
+		}
+	case *parse.CommandNode:
+		for _, a := range t.Args {
+			walkTemplateNode(a, refs)
+		}
+	case *parse.FieldNode:
+		*refs = append(*refs, strings.Join(t.Ident, "."))
+	case *parse.VariableNode:
+		*refs = append(*refs, strings.Join(t.Ident, "."))
+	case *parse.IfNode:
+		walkBranch(t.BranchNode, refs)
+	case *parse.RangeNode:
+		walkBranch(t.BranchNode, refs)
+	case *parse.WithNode:
+		walkBranch(t.BranchNode, refs)
+	case *parse.TemplateNode:
+		walkTemplateNode(t.Pipe, refs)
+	}
+}
+
+func walkBranch(b parse.BranchNode, refs *[]string) {
+	walkTemplateNode(b.Pipe, refs)
+	walkTemplateNode(b.List, refs)
+	walkTemplateNode(b.ElseList, refs)
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// -------------------------------------------------------------
+// PLUGGABLE VCS LAYER
+// -------------------------------------------------------------
+//
+// vcsCmd describes how to fetch a working copy from a single version
+// control system. This mirrors the table-driven approach used by the
+// Go tool's own vcs.go: each VCS is just a name, an executable, and a
+// function that builds the argument list for a shallow-equivalent
+// checkout into a destination directory.
+
+type vcsCmd struct {
+	name string
+	cmd  string
+
+	// cloneArgs returns the argv (excluding the executable itself) that
+	// performs the cheapest available "get me a working copy" operation
+	// for this VCS.
+	cloneArgs func(repoURL, dest string) []string
+
+	// postClone, if set, runs after cloneArgs's command succeeds and is
+	// given the chance to turn whatever cloneArgs produced into an actual
+	// working copy at dest. Most VCSes don't need this; Fossil does,
+	// since "fossil clone" only writes a repository database file and a
+	// separate "fossil open" is required to check files out of it.
+	postClone func(repoURL, dest string) []string
+}
+
+var vcsGit = &vcsCmd{
+	name: "Git",
+	cmd:  "git",
+	cloneArgs: func(repoURL, dest string) []string {
+		return []string{"clone", "--depth=1", repoURL, dest}
+	},
+}
+
+var vcsHg = &vcsCmd{
+	name: "Mercurial",
+	cmd:  "hg",
+	cloneArgs: func(repoURL, dest string) []string {
+		return []string{"clone", "--rev", "tip", repoURL, dest}
+	},
+}
+
+var vcsBzr = &vcsCmd{
+	name: "Bazaar",
+	cmd:  "bzr",
+	cloneArgs: func(repoURL, dest string) []string {
+		return []string{"branch", "--use-existing-dir", repoURL, dest}
+	},
+}
+
+var vcsFossil = &vcsCmd{
+	name: "Fossil",
+	cmd:  "fossil",
+	cloneArgs: func(repoURL, dest string) []string {
+		// Fossil has no notion of a shallow clone; "clone" only writes a
+		// repository database file next to dest. postClone below "opens"
+		// it inside dest to actually produce a working checkout.
+		return []string{"clone", repoURL, dest + ".fossil"}
+	},
+	postClone: func(repoURL, dest string) []string {
+		return []string{"open", "../" + dest + ".fossil"}
+	},
+}
+
+var vcsSvn = &vcsCmd{
+	name: "Subversion",
+	cmd:  "svn",
+	cloneArgs: func(repoURL, dest string) []string {
+		// svn has no "clone", and no shallow-equivalent that still
+		// populates subdirectories: --depth=immediates fetches nested
+		// dirs (cmd/, pkg/, internal/, ...) as empty placeholders, which
+		// would leave analyzeLocalRepo's walk finding nothing under
+		// them. Check out the full tree.
+		return []string{"checkout", "--depth=infinity", repoURL, dest}
+	},
+}
+
+// vcsHostPattern maps a set of host prefixes to the VCS that serves them.
+type vcsHostPattern struct {
+	hosts []string
+	vcs   *vcsCmd
+}
+
+// vcsByHost is checked in order; the first matching host prefix wins.
+var vcsByHost = []vcsHostPattern{
+	{hosts: []string{"bitbucket.org"}, vcs: vcsHg},
+	{hosts: []string{"launchpad.net"}, vcs: vcsBzr},
+	{hosts: []string{"code.google.com"}, vcs: vcsHg},
+	{hosts: []string{"chiselapp.com", "fossil-scm.org"}, vcs: vcsFossil},
+	{hosts: []string{"svn.apache.org", "sourceforge.net"}, vcs: vcsSvn},
+}
+
+// detectVCS infers which VCS serves repoPath (e.g. "bitbucket.org/ww/goautoneg"
+// or "github.com/user/repo") by matching its leading host component. It falls
+// back to git, since that's both the common case and the tool's original
+// behavior.
+func detectVCS(repoPath string) *vcsCmd {
+	host := repoPath
+	if idx := strings.Index(repoPath, "/"); idx != -1 {
+		host = repoPath[:idx]
+	}
+	for _, pattern := range vcsByHost {
+		for _, h := range pattern.hosts {
+			if host == h {
+				return pattern.vcs
+			}
+		}
+	}
+	return vcsGit
+}
+
+// cloneURLFor builds the URL to hand to vcs's clone command for repoPath.
+func cloneURLFor(vcs *vcsCmd, repoPath string) string {
+	switch vcs {
+	case vcsGit:
+		return "https://" + strings.TrimSuffix(repoPath, ".git") + ".git"
+	case vcsSvn:
+		return "https://" + repoPath + "/trunk"
+	default:
+		return "https://" + repoPath
+	}
+}
+
+// cloneRepo fetches a working copy using vcs's shallow-equivalent checkout
+// command, from repoURL into dest. If vcs needs a postClone step (Fossil's
+// "open", say) to turn what cloneArgs produced into an actual working copy
+// at dest, that runs afterwards with dest as its working directory.
+func cloneRepo(vcs *vcsCmd, repoURL, dest string) error {
+	logf("Cloning %s into %s via %s", repoURL, dest, vcs.name)
+	cmd := exec.Command(vcs.cmd, vcs.cloneArgs(repoURL, dest)...)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s clone of %s failed: %w", vcs.name, repoURL, err)
+	}
+
+	if vcs.postClone == nil {
+		return nil
+	}
+	if err := os.MkdirAll(dest, 0o755); err != nil {
+		return fmt.Errorf("%s: creating %s for post-clone: %w", vcs.name, dest, err)
+	}
+	post := exec.Command(vcs.cmd, vcs.postClone(repoURL, dest)...)
+	post.Dir = dest
+	if err := post.Run(); err != nil {
+		return fmt.Errorf("%s post-clone of %s failed: %w", vcs.name, repoURL, err)
+	}
+	return nil
+}